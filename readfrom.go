@@ -8,11 +8,34 @@ import (
 	"io"
 	"mime"
 	"mime/multipart"
+	"mime/quotedprintable"
 	"net/mail"
 	"net/textproto"
+	"os"
 	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// Errors returned by ReadFrom when a message trips one of the limits set
+// by SetMaxPartDepth, SetMaxPartCount, SetMaxPartSize or SetMaxTotalSize.
+var (
+	ErrMaxDepthExceeded = errors.New("gomail: maximum part nesting depth exceeded")
+	ErrMaxSizeExceeded  = errors.New("gomail: maximum part count or size exceeded")
 )
 
+// addressHeaders lists the headers whose values are address lists and
+// therefore need RFC 2047 decoding through mail.ParseAddressList rather
+// than plain header decoding.
+var addressHeaders = map[string]bool{
+	"From":     true,
+	"To":       true,
+	"Cc":       true,
+	"Bcc":      true,
+	"Reply-To": true,
+	"Sender":   true,
+}
+
 // ReadFrom implements io.ReadFrom. It parses a raw message into m.
 func (m *Message) ReadFrom(r io.Reader) (int64, error) {
 	mw := &messageReader{r: r}
@@ -20,27 +43,241 @@ func (m *Message) ReadFrom(r io.Reader) (int64, error) {
 	return mw.n, mw.err
 }
 
+// MessageFromEML builds a Message by parsing the EML read from r. The
+// settings are applied before parsing, so options such as
+// SetPreserveCharset take effect on the read.
+func MessageFromEML(r io.Reader, settings ...MessageSetting) (*Message, error) {
+	m := NewMessage(settings...)
+	_, err := m.ReadFrom(r)
+	return m, err
+}
+
+// MessageFromEMLFile builds a Message by parsing the EML file at path.
+func MessageFromEMLFile(path string, settings ...MessageSetting) (*Message, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return MessageFromEML(f, settings...)
+}
+
+// MessageFromEMLString builds a Message by parsing the EML held in eml.
+func MessageFromEMLString(eml string, settings ...MessageSetting) (*Message, error) {
+	return MessageFromEML(strings.NewReader(eml), settings...)
+}
+
+// SetPreserveCharset tells ReadFrom to keep body parts in their original
+// charset instead of transcoding them to UTF-8. It has no effect on
+// headers, which are always decoded to UTF-8.
+func SetPreserveCharset(preserve bool) MessageSetting {
+	return func(m *Message) {
+		m.preserveCharset = preserve
+	}
+}
+
+// SetMaxPartDepth bounds how deeply ReadFrom will recurse into nested
+// multipart/* parts before giving up with ErrMaxDepthExceeded. A value of
+// 0 (the default) means no limit.
+func SetMaxPartDepth(depth uint8) MessageSetting {
+	return func(m *Message) {
+		m.maxPartDepth = depth
+	}
+}
+
+// SetMaxPartCount bounds how many parts (of any kind) ReadFrom will parse
+// before giving up with ErrMaxSizeExceeded. A value of 0 (the default)
+// means no limit.
+func SetMaxPartCount(count int) MessageSetting {
+	return func(m *Message) {
+		m.maxPartCount = count
+	}
+}
+
+// SetMaxPartSize bounds the decoded size, in bytes, of any single part
+// ReadFrom will accept before giving up with ErrMaxSizeExceeded. A value
+// of 0 (the default) means no limit.
+func SetMaxPartSize(size int64) MessageSetting {
+	return func(m *Message) {
+		m.maxPartSize = size
+	}
+}
+
+// SetMaxTotalSize bounds the combined decoded size, in bytes, of all
+// parts ReadFrom will accept before giving up with ErrMaxSizeExceeded. A
+// value of 0 (the default) means no limit.
+func SetMaxTotalSize(size int64) MessageSetting {
+	return func(m *Message) {
+		m.maxTotalSize = size
+	}
+}
+
+// SetPreserveOriginal tells ReadFrom to additionally retain the raw
+// header block and the raw, pre-decode body of every part, so WriteTo
+// can reproduce the original message (original transfer encoding,
+// boundary strings, and the top-level header block byte for byte)
+// instead of the normalized form it otherwise emits. This keeps DKIM/ARC
+// signatures over the top-level headers and body parts intact when the
+// message is re-transmitted unchanged. The headers of parts inside a
+// multipart body are written back from their parsed form, with canonical
+// key casing and in no fixed order, and the preamble and epilogue of each
+// multipart body are not kept.
+func SetPreserveOriginal(preserve bool) MessageSetting {
+	return func(m *Message) {
+		m.preserveOriginal = preserve
+	}
+}
+
+// originalMessage holds the bytes ReadFrom captured for a message parsed
+// with SetPreserveOriginal: the raw top-level header block, plus a tree
+// mirroring the message's (possibly nested) multipart structure.
+type originalMessage struct {
+	header []byte
+	root   originalPart
+}
+
+// originalPart is the raw, pre-decode counterpart of a part of the
+// message body. A part with a non-empty boundary is itself a multipart
+// container and its content lives in children, delimited by that
+// boundary; otherwise body holds its raw bytes. header is nil for the
+// implicit top-level root, whose header is already part of
+// originalMessage.header.
+type originalPart struct {
+	header   textproto.MIMEHeader
+	boundary string
+	children []originalPart
+	body     []byte
+}
+
+// WriteTo writes the header block captured by SetPreserveOriginal
+// verbatim, followed by the body tree reconstructed with the original
+// boundary strings. WriteTo on Message calls this instead of re-encoding
+// the message when m.preserveOriginal is set.
+func (om *originalMessage) WriteTo(w io.Writer) (int64, error) {
+	written, err := w.Write(om.header)
+	n := int64(written)
+	if err != nil {
+		return n, err
+	}
+
+	bn, err := om.root.writeBody(w)
+	n += bn
+	return n, err
+}
+
+// writeTo writes p's header block, the blank line separating it from its
+// body, and its body (recursing into nested multiparts as needed). It is
+// never called for the implicit top-level root, whose header was already
+// written by originalMessage.WriteTo.
+func (p *originalPart) writeTo(w io.Writer) (int64, error) {
+	var n int64
+	for k, vs := range p.header {
+		for _, v := range vs {
+			hn, err := io.WriteString(w, k+": "+v+"\r\n")
+			n += int64(hn)
+			if err != nil {
+				return n, err
+			}
+		}
+	}
+
+	bn, err := io.WriteString(w, "\r\n")
+	n += int64(bn)
+	if err != nil {
+		return n, err
+	}
+
+	cn, err := p.writeBody(w)
+	n += cn
+	return n, err
+}
+
+// writeBody writes p's content: its raw body if p is a leaf, or its
+// children delimited by p.boundary ("--boundary" before each child,
+// "--boundary--" after the last) if p is a multipart container. Every
+// delimiter after the first starts with the CRLF that multipart.Part
+// strips from the end of the preceding child's body.
+func (p *originalPart) writeBody(w io.Writer) (int64, error) {
+	if p.boundary == "" {
+		written, err := w.Write(p.body)
+		return int64(written), err
+	}
+
+	var n int64
+	for i, child := range p.children {
+		delim := "--" + p.boundary + "\r\n"
+		if i > 0 {
+			delim = "\r\n" + delim
+		}
+		hn, err := io.WriteString(w, delim)
+		n += int64(hn)
+		if err != nil {
+			return n, err
+		}
+
+		cn, err := child.writeTo(w)
+		n += cn
+		if err != nil {
+			return n, err
+		}
+	}
+
+	closeDelim := "--" + p.boundary + "--\r\n"
+	if len(p.children) > 0 {
+		closeDelim = "\r\n" + closeDelim
+	}
+	tn, err := io.WriteString(w, closeDelim)
+	n += int64(tn)
+	return n, err
+}
+
+// WriteTo writes m to w. When m was parsed with SetPreserveOriginal and
+// still holds its captured bytes, it defers to m.original.WriteTo so the
+// original encoding, boundary strings and top-level header block survive
+// the round trip, instead of re-encoding the message the usual way.
+func (m *Message) WriteTo(w io.Writer) (int64, error) {
+	if m.preserveOriginal && m.original != nil {
+		return m.original.WriteTo(w)
+	}
+	return m.writeTo(w)
+}
+
 func (r *messageReader) readMessage(m *Message) {
 	// clear previous message
 	m.Reset()
 	m.charset = "UTF-8"
 	m.encoding = QuotedPrintable
 
-	// reads a message
+	// reads a message, teeing the raw bytes so the header block can be
+	// preserved verbatim when SetPreserveOriginal is set
+	var headerBuf bytes.Buffer
+	src := io.Reader(r.r)
+	if m.preserveOriginal {
+		src = io.TeeReader(r.r, &headerBuf)
+	}
+
 	var msg *mail.Message
-	msg, r.err = mail.ReadMessage(r.r)
+	msg, r.err = mail.ReadMessage(src)
 	if r.err != nil {
 		return
 	}
 
-	// copy headers, except Content-Type and Mime-Version
+	if m.preserveOriginal {
+		m.original = &originalMessage{header: rawHeaderBlock(headerBuf.Bytes())}
+	}
+
+	// copy headers, except Content-Type and Mime-Version, decoding any
+	// RFC 2047 encoded-words along the way
 	for hn, h := range msg.Header {
 		hc := textproto.CanonicalMIMEHeaderKey(hn)
-		switch hc {
-		case "Content-Type", "Mime-Version":
+		switch {
+		case hc == "Content-Type" || hc == "Mime-Version":
 			break
+		case addressHeaders[hc]:
+			m.header[hn] = r.decodeAddressHeader(h)
 		default:
-			m.header[hn] = h
+			m.header[hn] = r.decodeHeader(h)
 		}
 	}
 
@@ -64,10 +301,13 @@ func (r *messageReader) readMessage(m *Message) {
 			boundary = pboundary
 		}
 
-		r.parseMultipart(m, msg.Body, mediaType, boundary)
+		root := r.parseMultipart(m, msg.Body, mediaType, boundary, nil)
 		if r.err != nil {
 			return
 		}
+		if m.preserveOriginal {
+			m.original.root = root
+		}
 	} else {
 		// single body
 		ps := []PartSetting{
@@ -76,38 +316,92 @@ func (r *messageReader) readMessage(m *Message) {
 		if pencoding := msg.Header.Get("Content-Transfer-Encoding"); pencoding != "" {
 			ps = append(ps, SetPartEncoding(Encoding(pencoding)))
 		}
-		m.parts = []*part{m.newPart(mediaType, newReaderCopier(msg.Body), ps)}
+
+		var rawBuf bytes.Buffer
+		bodySrc := io.Reader(msg.Body)
+		if m.preserveOriginal {
+			bodySrc = io.TeeReader(msg.Body, &rawBuf)
+		}
+
+		body := r.readPartBody(m, bodySrc, Encoding(msg.Header.Get("Content-Transfer-Encoding")))
+		if r.err != nil {
+			return
+		}
+		if m.preserveOriginal {
+			m.original.root = originalPart{body: rawBuf.Bytes()}
+		}
+		if !m.preserveCharset {
+			body = r.decodeCharset(body, m.charset)
+			if r.err != nil {
+				return
+			}
+		}
+		m.parts = []*part{m.newPart(mediaType, newReaderCopier(body), ps)}
 	}
 }
 
-func (r *messageReader) parseMultipart(m *Message, mir io.Reader, mediaType string, boundary string) {
+// parseMultipart reads the parts of a multipart body whose media type is
+// mediaType. ancestors holds the media types of the multipart containers
+// that enclose mediaType itself, outermost first; it lets parsePart tell
+// a multipart/alternative body part from a multipart/mixed attachment
+// even when they're nested several levels deep.
+// parseMultipart returns the originalPart capturing this multipart
+// container, valid only when m.preserveOriginal is set.
+func (r *messageReader) parseMultipart(m *Message, mir io.Reader, mediaType string, boundary string, ancestors []string) originalPart {
+	r.depth++
+	defer func() { r.depth-- }()
+	if m.maxPartDepth > 0 && r.depth > m.maxPartDepth {
+		r.err = ErrMaxDepthExceeded
+		return originalPart{}
+	}
+
+	node := originalPart{boundary: boundary}
+
 	// multipart
 	mr := multipart.NewReader(mir, boundary)
+	childAncestors := append(append([]string{}, ancestors...), mediaType)
 	for {
 		var p *multipart.Part
-		p, r.err = mr.NextPart()
+		if m.preserveOriginal {
+			// NextPart would decode quoted-printable and drop its header
+			p, r.err = mr.NextRawPart()
+		} else {
+			p, r.err = mr.NextPart()
+		}
 		if r.err == io.EOF {
 			r.err = nil
 			break
 		}
 		if r.err != nil {
-			return
+			return node
 		}
 
 		// parse part
-		r.parsePart(m, p, mediaType)
+		child := r.parsePart(m, p, childAncestors)
 		if r.err != nil {
-			return
+			return node
+		}
+		if m.preserveOriginal {
+			node.children = append(node.children, child)
 		}
 	}
+	return node
 }
 
-func (r *messageReader) parsePart(m *Message, part *multipart.Part, parentMediaType string) {
+// parsePart returns the originalPart capturing this part, valid only when
+// m.preserveOriginal is set.
+func (r *messageReader) parsePart(m *Message, part *multipart.Part, ancestors []string) originalPart {
+	r.partCount++
+	if m.maxPartCount > 0 && r.partCount > m.maxPartCount {
+		r.err = ErrMaxSizeExceeded
+		return originalPart{}
+	}
+
 	var mediaType string
 	var params map[string]string
 	mediaType, params, r.err = mime.ParseMediaType(part.Header.Get("Content-Type"))
 	if r.err != nil {
-		return
+		return originalPart{}
 	}
 
 	if strings.HasPrefix(mediaType, "multipart/") {
@@ -117,91 +411,293 @@ func (r *messageReader) parsePart(m *Message, part *multipart.Part, parentMediaT
 			boundary = pboundary
 		}
 
-		r.parseMultipart(m, part, mediaType, boundary)
+		node := r.parseMultipart(m, part, mediaType, boundary, ancestors)
 		if r.err != nil {
-			return
+			return originalPart{}
 		}
-	} else {
-		// copy body bytes
-		body := r.readPartBody(part, Encoding(part.Header.Get("Content-Transfer-Encoding")))
+		if m.preserveOriginal {
+			node.header = part.Header
+		}
+		return node
+	}
+
+	// copy body bytes, teeing the raw, pre-decode bytes so the part can be
+	// preserved verbatim when SetPreserveOriginal is set
+	var rawBuf bytes.Buffer
+	bodySrc := io.Reader(part)
+	if m.preserveOriginal {
+		bodySrc = io.TeeReader(part, &rawBuf)
+	}
+
+	body := r.readPartBody(m, bodySrc, Encoding(part.Header.Get("Content-Transfer-Encoding")))
+	if r.err != nil {
+		return originalPart{}
+	}
+	var node originalPart
+	if m.preserveOriginal {
+		node = originalPart{header: part.Header, body: rawBuf.Bytes()}
+	}
+
+	// parse "name" from Content-Type as filename
+	var filename string
+	if pname, ok := params["name"]; ok {
+		filename = pname
+	}
+
+	// is the part inline, and does it carry a Content-ID? Most ordinary
+	// body parts never set Content-Disposition at all, so a missing
+	// header is treated as "" rather than as a parse error.
+	var contentDisposition string
+	var dispParams map[string]string
+	if cd := part.Header.Get("Content-Disposition"); cd != "" {
+		contentDisposition, dispParams, r.err = mime.ParseMediaType(cd)
 		if r.err != nil {
-			return
+			return originalPart{}
 		}
+	}
 
-		if parentMediaType == "multipart/alternative" {
-			// normal part
-			ps := []PartSetting{
-				SetPartHeaders(part.Header),
-			}
-			if pencoding := part.Header.Get("Content-Transfer-Encoding"); pencoding != "" {
-				ps = append(ps, SetPartEncoding(Encoding(pencoding)))
-			}
+	// if Content-Disposition has filename, prefer it over Content-Type's name
+	if pname, ok := dispParams["filename"]; ok {
+		filename = pname
+	}
 
-			m.parts = append(m.parts, m.newPart(mediaType, newReaderCopier(body), ps))
-		} else {
-			// attachment/embedded part
+	contentID := strings.Trim(part.Header.Get("Content-Id"), "<>")
 
-			// parse "name" from Content-Type as filename
-			var filename string
-			if pname, ok := params["name"]; ok {
-				filename = pname
-			}
+	switch {
+	case isBodyPart(mediaType, ancestors):
+		// normal part
+		ps := []PartSetting{
+			SetPartHeaders(part.Header),
+		}
+		if pencoding := part.Header.Get("Content-Transfer-Encoding"); pencoding != "" {
+			ps = append(ps, SetPartEncoding(Encoding(pencoding)))
+		}
 
-			// is file or attachment?
-			var contentDisposition string
-			contentDisposition, params, r.err = mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+		if !m.preserveCharset {
+			body = r.decodeCharset(body, firstNonEmpty(params["charset"], m.charset))
 			if r.err != nil {
-				return
+				return originalPart{}
 			}
+		}
 
-			// if Content-Disposition has filename, prefer it from Content-Type's name
-			if pname, ok := params["filename"]; ok {
-				filename = pname
-			}
+		m.parts = append(m.parts, m.newPart(mediaType, newReaderCopier(body), ps))
+	case contentDisposition == "inline" && contentID != "" && ancestorsContain(ancestors, "multipart/related"):
+		// embedded file referenced from a sibling body part via cid:
+		fs := []FileSetting{
+			SetHeader(part.Header),
+		}
+		m.embedded = m.appendFile(m.embedded, fileFromReader(firstNonEmpty(filename, contentID), body), fs)
+	default:
+		// attachment
+		if strings.TrimSpace(filename) == "" {
+			filename = firstNonEmpty(contentID, fmt.Sprintf("part-%d.bin", r.partCount))
+		}
 
-			// filename cannot be blank
-			if strings.TrimSpace(filename) == "" {
-				r.err = errors.New("Invalid blank file name")
-				return
-			}
+		fs := []FileSetting{
+			SetHeader(part.Header),
+		}
+		m.attachments = m.appendFile(m.attachments, fileFromReader(filename, body), fs)
+	}
 
-			// add embedded/attach
-			fs := []FileSetting{
-				SetHeader(part.Header),
-			}
+	return node
+}
 
-			if contentDisposition == "inline" {
-				m.embedded = m.appendFile(m.embedded, fileFromReader(filename, body), fs)
-			} else {
-				m.attachments = m.appendFile(m.attachments, fileFromReader(filename, body), fs)
-			}
+// isBodyPart reports whether a text/* part belongs in m.parts: its
+// nearest ancestor multipart, ignoring any multipart/related wrappers,
+// must be multipart/alternative or the top-level multipart itself, or
+// there must be no such ancestor at all.
+func isBodyPart(mediaType string, ancestors []string) bool {
+	if !strings.HasPrefix(mediaType, "text/") {
+		return false
+	}
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		if ancestors[i] == "multipart/related" {
+			continue
 		}
+		return ancestors[i] == "multipart/alternative" || i == 0
 	}
+	return true
 }
 
-// Read a part body, decoding if needed
-func (r *messageReader) readPartBody(part *multipart.Part, enc Encoding) io.Reader {
-	var body bytes.Buffer
-	if enc == Base64 {
+// ancestorsContain reports whether mediaType appears anywhere in ancestors.
+func ancestorsContain(ancestors []string, mediaType string) bool {
+	for _, a := range ancestors {
+		if a == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// SevenBit and Binary are, like Unencoded ("8bit"), identity transfer
+// encodings: readPartBody passes their bodies through unchanged.
+const (
+	SevenBit Encoding = "7bit"
+	Binary   Encoding = "binary"
+)
+
+// Read a part body, decoding if needed. enc is matched case-insensitively,
+// since Content-Transfer-Encoding values appear with arbitrary casing
+// (e.g. "BASE64") in the wild.
+func (r *messageReader) readPartBody(m *Message, body io.Reader, enc Encoding) io.Reader {
+	var decoded io.Reader
+	switch Encoding(strings.ToLower(string(enc))) {
+	case Base64:
 		// decode base64
-		_, r.err = body.ReadFrom(base64.NewDecoder(base64.StdEncoding, part))
-	} else if enc == Unencoded || enc == QuotedPrintable || enc == "" {
-		// multipart.Part already parses quoted-printable, and sets the header as blank
-		_, r.err = body.ReadFrom(part)
-	} else {
+		decoded = base64.NewDecoder(base64.StdEncoding, body)
+	case QuotedPrintable:
+		// a single-body message, or a part read with NextRawPart
+		decoded = quotedprintable.NewReader(body)
+	case Unencoded, SevenBit, Binary, "":
+		// multipart.Part.NextPart already parses quoted-printable, and sets the header as blank;
+		// 7bit/8bit/binary are pass-throughs
+		decoded = body
+	default:
 		r.err = fmt.Errorf("Unknown part encoding: %s", enc)
+		return nil
+	}
+
+	if m.maxPartSize > 0 {
+		// +1 lets us detect and report an overflow instead of silently
+		// truncating. This has to limit the decoded stream: base64
+		// expands ~4:3, so limiting the encoded input instead would cap
+		// the decoded output well under maxPartSize and the check below
+		// would never fire.
+		decoded = io.LimitReader(decoded, m.maxPartSize+1)
 	}
+
+	var buf bytes.Buffer
+	_, r.err = buf.ReadFrom(decoded)
 	if r.err != nil {
 		return nil
 	}
 
-	return &body
+	if m.maxPartSize > 0 && int64(buf.Len()) > m.maxPartSize {
+		r.err = ErrMaxSizeExceeded
+		return nil
+	}
+
+	r.totalSize += int64(buf.Len())
+	if m.maxTotalSize > 0 && r.totalSize > m.maxTotalSize {
+		r.err = ErrMaxSizeExceeded
+		return nil
+	}
+
+	return &buf
+}
+
+// decodeCharset transcodes body into UTF-8 according to charsetName.
+// Bodies already declared as UTF-8 or US-ASCII are returned unchanged,
+// since both are valid UTF-8 as-is.
+func (r *messageReader) decodeCharset(body io.Reader, charsetName string) io.Reader {
+	if !needsCharsetDecoding(charsetName) {
+		return body
+	}
+
+	cr, err := charset.NewReaderLabel(charsetName, body)
+	if err != nil {
+		r.err = err
+		return nil
+	}
+
+	var decoded bytes.Buffer
+	if _, r.err = decoded.ReadFrom(cr); r.err != nil {
+		return nil
+	}
+	return &decoded
+}
+
+// needsCharsetDecoding reports whether charsetName names a charset other
+// than UTF-8 or US-ASCII and therefore needs transcoding.
+func needsCharsetDecoding(charsetName string) bool {
+	switch strings.ToLower(strings.TrimSpace(charsetName)) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return false
+	default:
+		return true
+	}
+}
+
+// rawHeaderBlock trims the tail of a teed read down to the header block
+// mail.ReadMessage consumed, i.e. everything up to and including the
+// blank line that separates headers from the body. Read-ahead buffering
+// means b may contain a little of the body past that point.
+func rawHeaderBlock(b []byte) []byte {
+	if i := bytes.Index(b, []byte("\r\n\r\n")); i >= 0 {
+		return b[:i+4]
+	}
+	if i := bytes.Index(b, []byte("\n\n")); i >= 0 {
+		return b[:i+2]
+	}
+	return b
+}
+
+// firstNonEmpty returns the first non-empty string in values.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
 }
 
 type messageReader struct {
-	r          io.Reader
-	n          int64
-	partWriter io.Writer
-	depth      uint8
-	err        error
+	r           io.Reader
+	n           int64
+	partWriter  io.Writer
+	depth       uint8
+	partCount   int
+	totalSize   int64
+	err         error
+	wordDecoder *mime.WordDecoder
+}
+
+// decoder lazily builds the mime.WordDecoder used to decode RFC 2047
+// encoded-words, backed by golang.org/x/net/html/charset so headers
+// encoded in charsets other than UTF-8/US-ASCII are also understood.
+func (r *messageReader) decoder() *mime.WordDecoder {
+	if r.wordDecoder == nil {
+		r.wordDecoder = &mime.WordDecoder{
+			CharsetReader: func(label string, input io.Reader) (io.Reader, error) {
+				return charset.NewReaderLabel(label, input)
+			},
+		}
+	}
+	return r.wordDecoder
+}
+
+// decodeHeader decodes every value of a plain (non-address) header,
+// falling back to the original value if it isn't a valid encoded-word.
+func (r *messageReader) decodeHeader(h []string) []string {
+	dh := make([]string, len(h))
+	for i, v := range h {
+		if dv, err := r.decoder().DecodeHeader(v); err == nil {
+			dh[i] = dv
+		} else {
+			dh[i] = v
+		}
+	}
+	return dh
+}
+
+// decodeAddressHeader decodes each value of an address-bearing header
+// (From, To, Cc, ...) via mail.ParseAddressList, so display names that
+// use RFC 2047 encoded-words come back as decoded UTF-8.
+func (r *messageReader) decodeAddressHeader(h []string) []string {
+	ap := &mail.AddressParser{WordDecoder: r.decoder()}
+	dh := make([]string, len(h))
+	for i, v := range h {
+		addrs, err := ap.ParseList(v)
+		if err != nil {
+			dh[i] = v
+			continue
+		}
+		formatted := make([]string, len(addrs))
+		for j, addr := range addrs {
+			formatted[j] = FormatAddress(addr.Address, addr.Name)
+		}
+		dh[i] = strings.Join(formatted, ", ")
+	}
+	return dh
 }
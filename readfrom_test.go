@@ -0,0 +1,156 @@
+package gomail
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func TestMessageWriteToPreservesOriginalMultipart(t *testing.T) {
+	const eml = "From: a@example.com\r\n" +
+		"To: b@example.com\r\n" +
+		"Subject: test\r\n" +
+		"Content-Type: multipart/alternative; boundary=BOUNDARY\r\n" +
+		"\r\n" +
+		"--BOUNDARY\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"plain body\r\n" +
+		"--BOUNDARY\r\n" +
+		"Content-Type: text/html\r\n" +
+		"\r\n" +
+		"<p>html body</p>\r\n" +
+		"--BOUNDARY--\r\n"
+
+	m := NewMessage(SetPreserveOriginal(true))
+	if _, err := m.ReadFrom(strings.NewReader(eml)); err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+
+	var out bytes.Buffer
+	if _, err := m.WriteTo(&out); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+
+	// The output must be re-parseable MIME: a mail.Message whose
+	// multipart body splits cleanly into the two original parts.
+	msg, err := mail.ReadMessage(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("re-parsing WriteTo output: %v", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("parsing re-emitted Content-Type: %v", err)
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		t.Fatalf("expected a multipart Content-Type, got %q", mediaType)
+	}
+
+	var bodies []string
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("NextPart: %v", err)
+		}
+		b, err := io.ReadAll(p)
+		if err != nil {
+			t.Fatalf("reading re-emitted part: %v", err)
+		}
+		bodies = append(bodies, string(b))
+	}
+
+	want := []string{"plain body", "<p>html body</p>"}
+	if len(bodies) != len(want) {
+		t.Fatalf("got %d re-emitted parts, want %d: %q", len(bodies), len(want), bodies)
+	}
+	for i, b := range bodies {
+		if b != want[i] {
+			t.Errorf("part %d = %q, want %q", i, b, want[i])
+		}
+	}
+}
+
+func TestReadFromLimits(t *testing.T) {
+	const nestedMultipart = "From: a@example.com\r\n" +
+		"To: b@example.com\r\n" +
+		"Content-Type: multipart/mixed; boundary=OUTER\r\n" +
+		"\r\n" +
+		"--OUTER\r\n" +
+		"Content-Type: multipart/mixed; boundary=INNER\r\n" +
+		"\r\n" +
+		"--INNER\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"inner body\r\n" +
+		"--INNER--\r\n" +
+		"--OUTER--\r\n"
+
+	const threeParts = "From: a@example.com\r\n" +
+		"To: b@example.com\r\n" +
+		"Content-Type: multipart/mixed; boundary=BOUNDARY\r\n" +
+		"\r\n" +
+		"--BOUNDARY\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"one\r\n" +
+		"--BOUNDARY\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"two\r\n" +
+		"--BOUNDARY\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"three\r\n" +
+		"--BOUNDARY--\r\n"
+
+	const singleBody = "From: a@example.com\r\n" +
+		"To: b@example.com\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"this body is well over ten bytes long"
+
+	const twoParts = "From: a@example.com\r\n" +
+		"To: b@example.com\r\n" +
+		"Content-Type: multipart/mixed; boundary=BOUNDARY\r\n" +
+		"\r\n" +
+		"--BOUNDARY\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"0123456789\r\n" +
+		"--BOUNDARY\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"0123456789\r\n" +
+		"--BOUNDARY--\r\n"
+
+	tests := []struct {
+		name     string
+		eml      string
+		settings []MessageSetting
+		wantErr  error
+	}{
+		{"depth", nestedMultipart, []MessageSetting{SetMaxPartDepth(1)}, ErrMaxDepthExceeded},
+		{"count", threeParts, []MessageSetting{SetMaxPartCount(2)}, ErrMaxSizeExceeded},
+		{"part size", singleBody, []MessageSetting{SetMaxPartSize(10)}, ErrMaxSizeExceeded},
+		{"total size", twoParts, []MessageSetting{SetMaxPartSize(100), SetMaxTotalSize(15)}, ErrMaxSizeExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMessage(tt.settings...)
+			_, err := m.ReadFrom(strings.NewReader(tt.eml))
+			if err != tt.wantErr {
+				t.Fatalf("ReadFrom error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}